@@ -0,0 +1,460 @@
+package require
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+
+	js "github.com/dop251/goja"
+)
+
+// Bundle is a pre-resolved, self-contained snapshot of every module
+// reachable from a Compile() entry point: its source (after any
+// SourceTransformer has run) plus the specifier -> resolved-path graph
+// computed while walking it, plus every raw file (module source before
+// transform, and every package.json consulted along the way) read off the
+// backing SourceLoader during the walk, so a RequireModule using this
+// bundle never needs to touch the original SourceLoader again -- even for
+// the entry module itself, and even for directory/"exports" resolution
+// that falls outside Graph's specifier+caller edges. Deliberately, it does
+// not hold *goja.Program values directly -- those carry unexported
+// compiler state that doesn't survive encoding/gob -- so Save/ReadBundle
+// ship source text instead, and compilation happens once per process,
+// lazily, the first time a bundled module is actually required.
+type Bundle struct {
+	Entry   string
+	Modules map[string]string            // canonical module path -> source (post-transform)
+	Graph   map[string]map[string]string // caller path -> specifier -> resolved path
+	Files   map[string][]byte            // any path read via getSource during Compile, raw
+
+	mu       sync.Mutex
+	compiled map[string]*js.Program
+}
+
+// findRequireSpecifiers performs a single, comment/string/template-aware
+// lexical pass over src looking for `require("specifier")` calls (a
+// quoted string literal argument only -- a computed specifier like
+// `require(dep)` is left for normal runtime resolution rather than
+// silently mis-bundled, the same "lightweight walk" compromise
+// TranspileTypeScript makes for import/export). Unlike a plain regex, it
+// tracks comment and string/template spans the way commentOrTemplateMask
+// does for TranspileTypeScript, so a commented-out call or the substring
+// "require(" inside an unrelated string/identifier (e.g. "xrequire(")
+// is not mistaken for a real call.
+func findRequireSpecifiers(src string) []string {
+	runs := []rune(src)
+	var specifiers []string
+
+	inBlockComment := false
+	inTemplate := false
+
+	for i := 0; i < len(runs); i++ {
+		c := runs[i]
+
+		switch {
+		case inBlockComment:
+			if c == '*' && i+1 < len(runs) && runs[i+1] == '/' {
+				inBlockComment = false
+				i++
+			}
+			continue
+		case inTemplate:
+			if c == '\\' {
+				i++
+			} else if c == '`' {
+				inTemplate = false
+			}
+			continue
+		case c == '/' && i+1 < len(runs) && runs[i+1] == '/':
+			for i < len(runs) && runs[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && i+1 < len(runs) && runs[i+1] == '*':
+			inBlockComment = true
+			i++
+			continue
+		case c == '`':
+			inTemplate = true
+			continue
+		case c == '"' || c == '\'':
+			quote := c
+			i++
+			for i < len(runs) && runs[i] != quote {
+				if runs[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			continue
+		}
+
+		if !isIdentStart(c, runs, i) {
+			continue
+		}
+		if spec, end, ok := matchRequireCall(runs, i); ok {
+			specifiers = append(specifiers, spec)
+			i = end
+		}
+	}
+
+	return specifiers
+}
+
+// isIdentStart reports whether position i begins a fresh identifier, i.e.
+// it is not preceded by another identifier character -- so "require(" is
+// only considered at a word boundary and "xrequire(" is correctly ignored.
+func isIdentStart(c rune, runs []rune, i int) bool {
+	if !unicode.IsLetter(c) && c != '_' && c != '$' {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	prev := runs[i-1]
+	return !(unicode.IsLetter(prev) || unicode.IsDigit(prev) || prev == '_' || prev == '$')
+}
+
+// matchRequireCall checks whether runs[i:] begins with `require` followed
+// by a parenthesised, single quoted-string argument, and if so returns the
+// specifier and the index of the call's closing ')'.
+func matchRequireCall(runs []rune, i int) (specifier string, end int, ok bool) {
+	const kw = "require"
+	if i+len(kw) > len(runs) || string(runs[i:i+len(kw)]) != kw {
+		return "", 0, false
+	}
+	j := i + len(kw)
+	for j < len(runs) && unicode.IsSpace(runs[j]) {
+		j++
+	}
+	if j >= len(runs) || runs[j] != '(' {
+		return "", 0, false
+	}
+	j++
+	for j < len(runs) && unicode.IsSpace(runs[j]) {
+		j++
+	}
+	if j >= len(runs) || (runs[j] != '"' && runs[j] != '\'') {
+		return "", 0, false
+	}
+	quote := runs[j]
+	j++
+	start := j
+	for j < len(runs) && runs[j] != quote {
+		if runs[j] == '\\' {
+			j++
+		}
+		j++
+	}
+	if j >= len(runs) {
+		return "", 0, false
+	}
+	spec := string(runs[start:j])
+	j++ // closing quote
+	for j < len(runs) && unicode.IsSpace(runs[j]) {
+		j++
+	}
+	if j >= len(runs) || runs[j] != ')' {
+		return "", 0, false
+	}
+	return spec, j, true
+}
+
+// Compile performs a full static walk starting at entry, resolving every
+// literal require("...") reachable from it via findRequireSpecifiers, and
+// returns a Bundle containing their source, resolution graph, and every raw
+// file read along the way (module sources and package.json alike). Pass the
+// result to RequireModule.UseBundle so resolve() can look up each
+// specifier+caller pair in Graph directly -- skipping disk I/O and
+// re-parsing at runtime -- instead of walking the filesystem.
+func (r *Registry) Compile(entry string) (*Bundle, error) {
+	files := make(map[string][]byte)
+	read := func(path string) ([]byte, error) {
+		if buf, ok := files[path]; ok {
+			return buf, nil
+		}
+		buf, err := r.getSource(path)
+		if err != nil {
+			return nil, err
+		}
+		files[path] = buf
+		return buf, nil
+	}
+
+	entryPath, err := r.resolveStatic(entry, ".", read)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{
+		Entry:   entryPath,
+		Modules: make(map[string]string),
+		Graph:   make(map[string]map[string]string),
+	}
+
+	queue := []string{entryPath}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		if _, done := b.Modules[path]; done {
+			continue
+		}
+
+		src, err := read(path)
+		if err != nil {
+			return nil, err
+		}
+		if r.srcTransformer != nil {
+			if src, err = r.srcTransformer(path, src); err != nil {
+				return nil, err
+			}
+		}
+
+		if _, err := js.Compile(path, "(function(exports, require, module) {"+string(src)+"\n})", false); err != nil {
+			return nil, err
+		}
+
+		b.Modules[path] = string(src)
+		dir := filepath.Dir(path)
+		edges := make(map[string]string)
+
+		for _, specifier := range findRequireSpecifiers(string(src)) {
+			resolved, err := r.resolveStatic(specifier, dir, read)
+			if err != nil {
+				continue // left to fail (or succeed, e.g. a native module) at runtime
+			}
+			edges[specifier] = resolved
+			queue = append(queue, resolved)
+		}
+
+		if len(edges) > 0 {
+			b.Graph[path] = edges
+		}
+	}
+
+	// The very first require() of the program has no caller stack frame,
+	// so resolve() has no specifier+caller pair to look up for the entry
+	// module itself -- record it under the empty caller, keyed by the
+	// same specifier string the host passed to Compile, so the top-level
+	// require(entry) call resolves from the bundle too.
+	b.Graph[""] = map[string]string{entry: entryPath}
+
+	b.Files = files
+	return b, nil
+}
+
+// resolveStatic mirrors resolve.go's file/directory/node_modules search,
+// minus the cache and the running-runtime call-stack lookup that
+// RequireModule relies on for "the current module's directory" -- here
+// the caller's directory is threaded through explicitly instead, since
+// Compile walks sources rather than executing them. read is Compile's
+// recording wrapper around r.getSource, so every package.json this walk
+// consults ends up in the Bundle's Files alongside the module sources.
+func (r *Registry) resolveStatic(specifier, fromDir string, read sourceReader) (string, error) {
+	path := filepathClean(specifier)
+	if strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../") ||
+		strings.HasPrefix(specifier, "/") || specifier == "." || specifier == ".." {
+		start := fromDir
+		if strings.HasPrefix(specifier, "/") {
+			start = "/"
+		}
+		return r.staticLoadAsFileOrDirectory(filepath.Join(start, path), read)
+	}
+
+	for _, dir := range r.globalFolders {
+		if resolved, err := r.staticLoadNodeModule(path, dir, read); err == nil {
+			return resolved, nil
+		}
+	}
+
+	start := fromDir
+	for {
+		var nm string
+		if filepath.Base(start) != "node_modules" {
+			nm = filepath.Join(start, "node_modules")
+		} else {
+			nm = start
+		}
+		if resolved, err := r.staticLoadNodeModule(path, nm, read); err == nil {
+			return resolved, nil
+		}
+		if start == ".." {
+			break
+		}
+		parent := filepath.Dir(start)
+		if parent == start {
+			break
+		}
+		start = parent
+	}
+
+	return "", InvalidModuleError
+}
+
+// sourceReader is the subset of SourceLoader's signature Compile's static
+// walk reads through, so every call site can be handed either a live
+// Registry.getSource or Compile's recording wrapper around it.
+type sourceReader func(path string) ([]byte, error)
+
+func (r *Registry) staticLoadNodeModule(path, dir string, read sourceReader) (string, error) {
+	pkgName, subpath := splitPackageSpecifier(path)
+	pkgDir := filepath.Join(dir, pkgName)
+
+	if buf, err := read(filepath.Join(pkgDir, "package.json")); err == nil {
+		var pkg packageJSON
+		if json.Unmarshal(buf, &pkg) == nil {
+			if rel, ok := resolveExportsSubpath(pkg.Exports, subpath, r.conditions); ok {
+				if resolved, err := r.staticLoadAsFile(filepath.Join(pkgDir, rel), read); err == nil {
+					return resolved, nil
+				}
+			}
+		}
+	}
+
+	return r.staticLoadAsFileOrDirectory(filepath.Join(dir, path), read)
+}
+
+func (r *Registry) staticLoadAsFileOrDirectory(path string, read sourceReader) (string, error) {
+	if resolved, err := r.staticLoadAsFile(path, read); err == nil {
+		return resolved, nil
+	}
+	return r.staticLoadAsDirectory(path, read)
+}
+
+func (r *Registry) staticLoadAsFile(path string, read sourceReader) (string, error) {
+	for _, candidate := range []string{path, path + ".js", path + ".ts", path + ".tsx", path + ".json"} {
+		if _, err := read(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", InvalidModuleError
+}
+
+func (r *Registry) staticLoadIndex(path string, read sourceReader) (string, error) {
+	for _, candidate := range []string{filepath.Join(path, "index.js"), filepath.Join(path, "index.json")} {
+		if _, err := read(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", InvalidModuleError
+}
+
+func (r *Registry) staticLoadAsDirectory(path string, read sourceReader) (string, error) {
+	buf, err := read(filepath.Join(path, "package.json"))
+	if err != nil {
+		return r.staticLoadIndex(path, read)
+	}
+	var pkg packageJSON
+	if json.Unmarshal(buf, &pkg) != nil {
+		return r.staticLoadIndex(path, read)
+	}
+
+	main, ok := resolveExportsSubpath(pkg.Exports, ".", r.conditions)
+	if !ok {
+		main, ok = pkg.mainField()
+	}
+	if !ok {
+		return r.staticLoadIndex(path, read)
+	}
+
+	m := filepath.Join(path, main)
+	if resolved, err := r.staticLoadAsFile(m, read); err == nil {
+		return resolved, nil
+	}
+	return r.staticLoadIndex(m, read)
+}
+
+// UseBundle switches r to serving any module whose resolved path is in
+// b.Modules directly from the bundle, compiling each one lazily on first
+// use (and caching the result on the bundle so later requires, even from
+// a different RequireModule using the same Bundle, are free). Modules
+// outside the bundle continue to resolve normally.
+func (r *RequireModule) UseBundle(b *Bundle) {
+	r.bundle = b
+}
+
+// resolvedPath looks up the specifier+caller pair in the bundle's
+// pre-computed resolution graph, letting resolve() short-circuit the
+// filesystem walk (and its getSource/package.json calls) entirely for
+// anything Compile already reached. callerFile == "" is the top-level
+// require() call, which has no caller JS stack frame of its own -- Compile
+// records that case under Graph[""], keyed by the same specifier string
+// originally passed to Compile, so it resolves here too.
+func (b *Bundle) resolvedPath(callerFile, specifier string) (string, bool) {
+	edges, ok := b.Graph[callerFile]
+	if !ok {
+		return "", false
+	}
+	resolved, ok := edges[specifier]
+	return resolved, ok
+}
+
+// getSource serves path from the bundle's recorded Files, falling back to
+// ok == false when Compile's static walk never read it -- e.g. a
+// dynamically computed specifier outside the static graph. Callers fall
+// back to the live Registry.getSource in that case.
+func (b *Bundle) getSource(path string) ([]byte, bool) {
+	buf, ok := b.Files[path]
+	return buf, ok
+}
+
+func (b *Bundle) program(path string) (*js.Program, bool, error) {
+	src, ok := b.Modules[path]
+	if !ok {
+		return nil, false, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.compiled == nil {
+		b.compiled = make(map[string]*js.Program)
+	}
+	if prg := b.compiled[path]; prg != nil {
+		return prg, true, nil
+	}
+
+	source := "(function(exports, require, module) {" + src + "\n})"
+	prg, err := js.Compile(path, source, false)
+	if err != nil {
+		return nil, true, err
+	}
+	b.compiled[path] = prg
+	return prg, true, nil
+}
+
+// Save serialises b, including its source text, resolution graph and
+// recorded files, so it can ship alongside the Go binary and be restored
+// with ReadBundle without needing the original module tree on disk. Named
+// Save rather than WriteTo since it doesn't return the io.WriterTo byte
+// count -- gob's Encoder doesn't report one.
+func (b *Bundle) Save(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	if err := gob.NewEncoder(bw).Encode(struct {
+		Entry   string
+		Modules map[string]string
+		Graph   map[string]map[string]string
+		Files   map[string][]byte
+	}{b.Entry, b.Modules, b.Graph, b.Files}); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ReadBundle restores a Bundle previously written with Bundle.Save.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	var raw struct {
+		Entry   string
+		Modules map[string]string
+		Graph   map[string]map[string]string
+		Files   map[string][]byte
+	}
+	if err := gob.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+	return &Bundle{Entry: raw.Entry, Modules: raw.Modules, Graph: raw.Graph, Files: raw.Files}, nil
+}
@@ -0,0 +1,59 @@
+package require
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	js "github.com/dop251/goja"
+)
+
+// TestUseBundleIsDiskFree compiles a small program (an entry that requires
+// a relative sibling and a node_modules package resolved via "main"), then
+// runs it through a RequireModule whose SourceLoader always errors -- the
+// only way any of it can resolve is if UseBundle serves the entry itself,
+// the sibling, and the node_modules package entirely out of the Bundle,
+// with no fallback to the (failing) live loader.
+func TestUseBundleIsDiskFree(t *testing.T) {
+	sources := map[string]string{
+		"/app/main.js":                       `module.exports.value = require("./lib").value + require("dep").value;`,
+		"/app/lib.js":                        `module.exports.value = 1;`,
+		"/app/node_modules/dep/package.json": `{"main": "./index.js"}`,
+		"/app/node_modules/dep/index.js":     `module.exports.value = 41;`,
+	}
+
+	compileReg := NewRegistry(WithLoader(func(path string) ([]byte, error) {
+		if s, ok := sources[path]; ok {
+			return []byte(s), nil
+		}
+		return nil, errors.New("not found: " + path)
+	}))
+
+	bundle, err := compileReg.Compile("/app/main.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := bundle.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := ReadBundle(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diskless := NewRegistry(WithLoader(func(path string) ([]byte, error) {
+		return nil, errors.New("disk access attempted for: " + path)
+	}))
+
+	runtime := js.New()
+	rm := diskless.Enable(runtime)
+	rm.UseBundle(restored)
+
+	exports := Require(runtime, "/app/main.js")
+	value := exports.ToObject(runtime).Get("value").ToInteger()
+	if value != 42 {
+		t.Fatalf("expected value 42, got %d", value)
+	}
+}
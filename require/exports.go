@@ -0,0 +1,145 @@
+package require
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// packageJSON mirrors the subset of package.json fields consulted while
+// resolving a directory/package to a module file. Browser is left as raw
+// JSON because real-world package.json files commonly give it as an object
+// mapping replaced module paths to their browser substitute (e.g.
+// {"fs": false}) rather than a single entry-point string; decoding that
+// form into a plain string would fail the whole Unmarshal.
+type packageJSON struct {
+	Main    string          `json:"main"`
+	Module  string          `json:"module"`
+	Browser json.RawMessage `json:"browser"`
+	Exports json.RawMessage `json:"exports"`
+}
+
+// mainField returns the best legacy entry point, falling back through
+// "main" -> "module" -> "browser" in that order, as loadAsDirectory did
+// before "exports" existed. The "browser" field only counts here when it is
+// given in its string-entry-point form; the object (path-remapping) form
+// has no single main file to offer and is simply skipped.
+func (p *packageJSON) mainField() (string, bool) {
+	if p.Main != "" {
+		return p.Main, true
+	}
+	if p.Module != "" {
+		return p.Module, true
+	}
+	if browser, ok := p.browserMain(); ok {
+		return browser, true
+	}
+	return "", false
+}
+
+// browserMain reports the "browser" field's value when it was given as a
+// plain string, i.e. the legacy single-entry-point form.
+func (p *packageJSON) browserMain() (string, bool) {
+	if len(p.Browser) == 0 {
+		return "", false
+	}
+	var s string
+	if err := json.Unmarshal(p.Browser, &s); err != nil || s == "" {
+		return "", false
+	}
+	return s, true
+}
+
+// resolveExportsSubpath resolves subpath (either "." for the package root,
+// or "./foo" for a named export) against the package.json "exports" value,
+// honouring conditions in priority order. ok is false if "exports" doesn't
+// cover subpath at all, in which case the caller should fall back to the
+// legacy main/module/browser/index resolution.
+func resolveExportsSubpath(exportsRaw json.RawMessage, subpath string, conditions []string) (rel string, ok bool) {
+	if len(exportsRaw) == 0 {
+		return "", false
+	}
+
+	var target interface{}
+	if err := json.Unmarshal(exportsRaw, &target); err != nil {
+		return "", false
+	}
+
+	// String form: exports: "./index.js" is shorthand for exports: {".": "./index.js"}.
+	if s, isString := target.(string); isString {
+		if subpath == "." {
+			return s, true
+		}
+		return "", false
+	}
+
+	m, isMap := target.(map[string]interface{})
+	if !isMap {
+		return "", false
+	}
+
+	if isSubpathMap(m) {
+		if v, present := m[subpath]; present {
+			return resolveCondition(v, conditions)
+		}
+		return resolveSubpathPattern(m, subpath, conditions)
+	}
+
+	// Conditional object form applies only to the package root.
+	if subpath == "." {
+		return resolveCondition(m, conditions)
+	}
+
+	return "", false
+}
+
+// isSubpathMap reports whether m's keys are subpaths ("." or "./foo")
+// rather than condition names ("require", "node", "default", ...).
+func isSubpathMap(m map[string]interface{}) bool {
+	for k := range m {
+		if k == "." || strings.HasPrefix(k, "./") {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSubpathPattern matches subpath against a "./*" pattern key,
+// substituting the matched remainder for the "*" in the target pattern.
+func resolveSubpathPattern(m map[string]interface{}, subpath string, conditions []string) (string, bool) {
+	pattern, present := m["./*"]
+	if !present {
+		return "", false
+	}
+	rest := strings.TrimPrefix(subpath, "./")
+	target, ok := resolveCondition(pattern, conditions)
+	if !ok {
+		return "", false
+	}
+	return strings.Replace(target, "*", rest, 1), true
+}
+
+// resolveCondition walks a package.json exports value, which is either a
+// plain string (a direct target) or an object keyed by condition name
+// whose values are themselves resolved recursively, in the order given by
+// conditions. "default" always matches last if present.
+func resolveCondition(v interface{}, conditions []string) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case map[string]interface{}:
+		for _, cond := range conditions {
+			if cond == "default" {
+				continue
+			}
+			if val, present := t[cond]; present {
+				if s, ok := resolveCondition(val, conditions); ok {
+					return s, true
+				}
+			}
+		}
+		if val, present := t["default"]; present {
+			return resolveCondition(val, conditions)
+		}
+	}
+	return "", false
+}
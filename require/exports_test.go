@@ -0,0 +1,55 @@
+package require
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestPackageJSONBrowserObjectForm guards against the object (path
+// remapping) form of "browser" -- e.g. {"fs": false} -- breaking
+// json.Unmarshal for the whole package.json, which previously happened
+// because Browser was declared as a plain string.
+func TestPackageJSONBrowserObjectForm(t *testing.T) {
+	var pkg packageJSON
+	if err := json.Unmarshal([]byte(`{"main":"./index.js","browser":{"fs":false}}`), &pkg); err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	main, ok := pkg.mainField()
+	if !ok || main != "./index.js" {
+		t.Fatalf("expected mainField to fall back to main %q, got %q (ok=%v)", "./index.js", main, ok)
+	}
+}
+
+// TestPackageJSONBrowserStringForm confirms the legacy single-entry-point
+// "browser" string is still honoured when "main" and "module" are absent.
+func TestPackageJSONBrowserStringForm(t *testing.T) {
+	var pkg packageJSON
+	if err := json.Unmarshal([]byte(`{"browser":"./browser.js"}`), &pkg); err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	main, ok := pkg.mainField()
+	if !ok || main != "./browser.js" {
+		t.Fatalf("expected mainField %q, got %q (ok=%v)", "./browser.js", main, ok)
+	}
+}
+
+// TestResolveExportsSubpathConditionsAndPattern covers the "exports"
+// resolution path end to end: a conditional object for the package root
+// and a "./*" subpath pattern, resolved against a non-default condition
+// order.
+func TestResolveExportsSubpathConditionsAndPattern(t *testing.T) {
+	exports := []byte(`{
+		".": {"node": "./node.js", "default": "./index.js"},
+		"./*": "./src/*.js"
+	}`)
+
+	if rel, ok := resolveExportsSubpath(exports, ".", []string{"node", "default"}); !ok || rel != "./node.js" {
+		t.Fatalf("expected root to resolve via node condition, got %q (ok=%v)", rel, ok)
+	}
+	if rel, ok := resolveExportsSubpath(exports, ".", []string{"browser", "default"}); !ok || rel != "./index.js" {
+		t.Fatalf("expected root to fall back to default condition, got %q (ok=%v)", rel, ok)
+	}
+	if rel, ok := resolveExportsSubpath(exports, "./foo", []string{"node", "default"}); !ok || rel != "./src/foo.js" {
+		t.Fatalf("expected pattern match, got %q (ok=%v)", rel, ok)
+	}
+}
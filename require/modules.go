@@ -0,0 +1,249 @@
+package require
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	js "github.com/dop251/goja"
+)
+
+const ModuleName = "require"
+
+// ModuleLoader is a function that is called when a native (built-in) module
+// is required. It receives the runtime and the module object and is
+// expected to populate module.exports.
+type ModuleLoader func(*js.Runtime, *js.Object)
+
+// SourceLoader is called when a module source needs to be read from its
+// backing store. The default implementation reads from the local
+// filesystem, but it can be replaced (see WithLoader) to serve sources
+// from anywhere, e.g. an in-memory archive.
+type SourceLoader func(path string) ([]byte, error)
+
+var (
+	InvalidModuleError       = errors.New("invalid module")
+	IllegalModuleNameError   = errors.New("illegal module name")
+	NoSuchBuiltInModuleError = errors.New("no such built-in module")
+)
+
+var native = make(map[string]ModuleLoader)
+
+// RegisterNativeModule registers a global native module that will be
+// available to every Registry. Should be called from an init() function.
+func RegisterNativeModule(name string, loader ModuleLoader) {
+	name = filepathClean(name)
+	native[name] = loader
+}
+
+func filepathClean(p string) string {
+	return filepath.Clean(p)
+}
+
+// Option configures a Registry.
+type Option func(*Registry)
+
+// WithLoader sets the SourceLoader used to resolve module sources. If not
+// set, sources are read from the local filesystem with ioutil.ReadFile.
+func WithLoader(srcLoader SourceLoader) Option {
+	return func(r *Registry) {
+		r.srcLoader = srcLoader
+	}
+}
+
+// SourceTransformer rewrites a module's raw source, identified by its
+// resolved path, before it is handed to goja for compilation. Returning
+// the input unchanged is always a valid (no-op) implementation.
+type SourceTransformer func(path string, src []byte) ([]byte, error)
+
+// WithSourceTransformer installs a hook that runs on every module's source
+// right before compilation, e.g. to strip TypeScript annotations or
+// rewrite ES module syntax into CommonJS (see TranspileTypeScript).
+func WithSourceTransformer(t SourceTransformer) Option {
+	return func(r *Registry) {
+		r.srcTransformer = t
+	}
+}
+
+// WithGlobalFolders sets the list of folders (replacing any set earlier)
+// that are searched for node_modules in addition to the ones found by walking
+// up from the requiring module, mirroring Node's NODE_PATH.
+func WithGlobalFolders(globalFolders ...string) Option {
+	return func(r *Registry) {
+		r.globalFolders = globalFolders
+	}
+}
+
+// Registry contains a cache of compiled modules that can be shared by
+// multiple runtimes (each via its own RequireModule, created by Enable).
+type Registry struct {
+	sync.Mutex
+
+	native   map[string]ModuleLoader
+	compiled map[string]*js.Program
+
+	srcLoader      SourceLoader
+	globalFolders  []string
+	watcher        Watcher
+	conditions     []string
+	srcTransformer SourceTransformer
+	resolveTracer  func(ResolveEvent)
+
+	// zipMountPoint/zipLoader back WithZipArchive. They're kept separate
+	// from srcLoader, rather than srcLoader wrapping around whatever was
+	// set before it, so WithZipArchive and WithLoader can be passed to
+	// NewRegistry in either order -- getSource consults zipLoader first
+	// and falls back to srcLoader, instead of one option's closure
+	// silently capturing (and losing) the other's.
+	zipMountPoint string
+	zipLoader     SourceLoader
+}
+
+// defaultConditions are the package.json "exports" conditions consulted
+// when no WithConditions option is given, matching Node's CommonJS default.
+var defaultConditions = []string{"require", "node", "default"}
+
+// NewRegistry creates a new module registry.
+func NewRegistry(opts ...Option) *Registry {
+	r := &Registry{
+		native:     make(map[string]ModuleLoader),
+		conditions: defaultConditions,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// WithConditions sets the package.json "exports" conditions that are
+// considered a match, in priority order. The "default" condition, if
+// present, always matches last. If not set, the Registry uses
+// ["require", "node", "default"].
+func WithConditions(conditions []string) Option {
+	return func(r *Registry) {
+		r.conditions = conditions
+	}
+}
+
+// RegisterNativeModule registers a native module with this registry only.
+func (r *Registry) RegisterNativeModule(name string, loader ModuleLoader) {
+	name = filepathClean(name)
+	r.native[name] = loader
+}
+
+func (r *Registry) getSource(p string) ([]byte, error) {
+	if r.zipLoader != nil {
+		if rel, ok := underMountPoint(p, r.zipMountPoint); ok {
+			return r.zipLoader(rel)
+		}
+	}
+
+	srcLoader := r.srcLoader
+	if srcLoader == nil {
+		srcLoader = ioutil.ReadFile
+	}
+	return srcLoader(p)
+}
+
+func (r *Registry) getCompiledSource(p string) (*js.Program, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	if prg := r.compiled[p]; prg != nil {
+		return prg, nil
+	}
+
+	buf, err := r.getSource(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.srcTransformer != nil {
+		buf, err = r.srcTransformer(p, buf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := string(buf)
+	if strings.HasPrefix(s, "#!") { // shebang
+		s = "//" + s[2:]
+	}
+
+	source := "(function(exports, require, module) {" + s + "\n})"
+	prg, err := js.Compile(p, source, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.compiled == nil {
+		r.compiled = make(map[string]*js.Program)
+	}
+	r.compiled[p] = prg
+
+	return prg, nil
+}
+
+// Enable adds the require() function to the given runtime and returns the
+// RequireModule that backs it, so host code can interact with module
+// resolution (e.g. invalidating caches).
+func (r *Registry) Enable(runtime *js.Runtime) *RequireModule {
+	rm := &RequireModule{
+		r:                 r,
+		runtime:           runtime,
+		modules:           make(map[string]*js.Object),
+		nodeModules:       make(map[string]*js.Object),
+		moduleAliases:     make(map[string][]string),
+		nodeModuleAliases: make(map[string][]string),
+		watcher:           r.watcher,
+	}
+
+	runtime.Set("require", rm.require)
+
+	return rm
+}
+
+// RequireModule ties a Registry to a single goja.Runtime.
+type RequireModule struct {
+	r       *Registry
+	runtime *js.Runtime
+
+	modules     map[string]*js.Object
+	nodeModules map[string]*js.Object
+
+	// moduleAliases/nodeModuleAliases map a real, extension-resolved path
+	// (the key loadModule caches under) to every pre-resolution specifier
+	// key resolve() also cached it under (e.g. "./foo" -> "foo.js"), so
+	// Invalidate can evict both when a watched file changes.
+	moduleAliases     map[string][]string
+	nodeModuleAliases map[string][]string
+
+	watcher        Watcher
+	onModuleReload func(path string, newExports js.Value)
+	bundle         *Bundle
+}
+
+func (r *RequireModule) require(call js.FunctionCall) js.Value {
+	ret, err := r.resolve(call.Argument(0).String())
+	if err != nil {
+		panic(r.runtime.NewGoError(err))
+	}
+	return ret.Get("exports")
+}
+
+// Require can be used to import modules from Go source (similar to
+// JavaScript require() function).
+func Require(runtime *js.Runtime, name string) js.Value {
+	if r, ok := js.AssertFunction(runtime.Get("require")); ok {
+		mod, err := r(js.Undefined(), runtime.ToValue(name))
+		if err != nil {
+			panic(err)
+		}
+		return mod
+	}
+	panic(runtime.NewTypeError("Please enable require for this runtime using new(require.Registry).Enable(runtime)"))
+}
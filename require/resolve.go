@@ -21,32 +21,62 @@ func (r *RequireModule) resolve(path string) (module *js.Object, err error) {
 		return
 	}
 
+	callerFile := r.getCurrentModuleFile()
+
 	var start string
 	err = nil
 	if strings.HasPrefix(origPath, "/") {
 		start = "/"
+	} else if callerFile != "" {
+		start = filepath.Dir(callerFile)
 	} else {
-		start = r.getCurrentModulePath()
+		start = "."
 	}
 
+	trace := &resolveTrace{specifier: origPath, start: start, tracer: r.r.resolveTracer}
+
 	p := filepath.Join(start, path)
+
+	if r.bundle != nil {
+		if resolved, ok := r.bundle.resolvedPath(callerFile, origPath); ok {
+			if module, err = r.loadModule(resolved, trace); err == nil {
+				if strings.HasPrefix(origPath, "./") ||
+					strings.HasPrefix(origPath, "/") || strings.HasPrefix(origPath, "../") ||
+					origPath == "." || origPath == ".." {
+					r.modules[p] = module
+					r.recordAlias(r.moduleAliases, resolved, p)
+				} else {
+					r.nodeModules[p] = module
+					r.recordAlias(r.nodeModuleAliases, resolved, p)
+				}
+			}
+			return
+		}
+	}
+
 	if strings.HasPrefix(origPath, "./") ||
 		strings.HasPrefix(origPath, "/") || strings.HasPrefix(origPath, "../") ||
 		origPath == "." || origPath == ".." {
 		if module = r.modules[p]; module != nil {
 			return
 		}
-		module, err = r.loadAsFileOrDirectory(p)
+		module, err = r.loadAsFileOrDirectory(p, trace)
 		if err == nil {
 			r.modules[p] = module
+			r.recordAlias(r.moduleAliases, trace.resolved, p)
+		} else {
+			err = trace.asError()
 		}
 	} else {
 		if module = r.nodeModules[p]; module != nil {
 			return
 		}
-		module, err = r.loadNodeModules(path, start)
+		module, err = r.loadNodeModules(path, start, trace)
 		if err == nil {
 			r.nodeModules[p] = module
+			r.recordAlias(r.nodeModuleAliases, trace.resolved, p)
+		} else {
+			err = trace.asError()
 		}
 	}
 
@@ -74,68 +104,140 @@ func (r *RequireModule) loadNative(path string) (*js.Object, error) {
 	return nil, InvalidModuleError
 }
 
-func (r *RequireModule) loadAsFileOrDirectory(path string) (module *js.Object, err error) {
-	module, err = r.loadAsFile(path)
+func (r *RequireModule) loadAsFileOrDirectory(path string, trace *resolveTrace) (module *js.Object, err error) {
+	module, err = r.loadAsFile(path, trace)
 	if err == nil {
 		return
 	}
 
-	return r.loadAsDirectory(path)
+	return r.loadAsDirectory(path, trace)
 }
 
-func (r *RequireModule) loadAsFile(path string) (module *js.Object, err error) {
-	if module, err = r.loadModule(path); err == nil {
+func (r *RequireModule) loadAsFile(path string, trace *resolveTrace) (module *js.Object, err error) {
+	if module, err = r.loadModule(path, trace); err == nil {
 		return
 	}
 
 	p := path + ".js"
-	if module, err = r.loadModule(p); err == nil {
+	if module, err = r.loadModule(p, trace); err == nil {
+		return
+	}
+
+	p = path + ".ts"
+	if module, err = r.loadModule(p, trace); err == nil {
+		return
+	}
+
+	p = path + ".tsx"
+	if module, err = r.loadModule(p, trace); err == nil {
 		return
 	}
 
 	p = path + ".json"
-	return r.loadModule(p)
+	return r.loadModule(p, trace)
 }
 
-func (r *RequireModule) loadIndex(path string) (module *js.Object, err error) {
+func (r *RequireModule) loadIndex(path string, trace *resolveTrace) (module *js.Object, err error) {
 	p := filepath.Join(path, "index.js")
-	if module, err = r.loadModule(p); err == nil {
+	if module, err = r.loadModule(p, trace); err == nil {
 		return
 	}
 
 	p = filepath.Join(path, "index.json")
-	return r.loadModule(p)
+	return r.loadModule(p, trace)
 }
 
-func (r *RequireModule) loadAsDirectory(path string) (module *js.Object, err error) {
+func (r *RequireModule) loadAsDirectory(path string, trace *resolveTrace) (module *js.Object, err error) {
 	p := filepath.Join(path, "package.json")
-	buf, err := r.r.getSource(p)
+	buf, err := r.getSource(p)
 	if err != nil {
-		return r.loadIndex(path)
-	}
-	var pkg struct {
-		Main string
+		trace.record(p, err)
+		return r.loadIndex(path, trace)
 	}
+	var pkg packageJSON
 	err = json.Unmarshal(buf, &pkg)
-	if err != nil || len(pkg.Main) == 0 {
-		return r.loadIndex(path)
+	if err != nil {
+		trace.record(p, err)
+		return r.loadIndex(path, trace)
+	}
+
+	main, ok := resolveExportsSubpath(pkg.Exports, ".", r.r.conditions)
+	if !ok {
+		main, ok = pkg.mainField()
+	}
+	if !ok {
+		return r.loadIndex(path, trace)
 	}
 
-	m := filepath.Join(path, pkg.Main)
-	if module, err = r.loadAsFile(m); err == nil {
+	m := filepath.Join(path, main)
+	if module, err = r.loadAsFile(m, trace); err == nil {
 		return
 	}
 
-	return r.loadIndex(m)
+	return r.loadIndex(m, trace)
+}
+
+// loadPackageExports resolves a bare specifier ("pkg" or "pkg/sub/path")
+// to a file within pkgDir using pkgDir's package.json "exports" map, so
+// subpath exports (e.g. "./foo" -> "./lib/foo.js") and the "./*" pattern
+// form take effect before the plain filesystem walk in loadAsFileOrDirectory.
+func (r *RequireModule) loadPackageExports(pkgDir, subpath string, trace *resolveTrace) (module *js.Object, err error) {
+	p := filepath.Join(pkgDir, "package.json")
+	buf, err := r.getSource(p)
+	if err != nil {
+		trace.record(p, err)
+		return nil, InvalidModuleError
+	}
+	var pkg packageJSON
+	if err = json.Unmarshal(buf, &pkg); err != nil || len(pkg.Exports) == 0 {
+		if err == nil {
+			err = InvalidModuleError
+		}
+		trace.record(p, err)
+		return nil, InvalidModuleError
+	}
+
+	rel, ok := resolveExportsSubpath(pkg.Exports, subpath, r.r.conditions)
+	if !ok {
+		trace.record(p, InvalidModuleError)
+		return nil, InvalidModuleError
+	}
+
+	return r.loadAsFile(filepath.Join(pkgDir, rel), trace)
 }
 
-func (r *RequireModule) loadNodeModule(path, start string) (*js.Object, error) {
-	return r.loadAsFileOrDirectory(filepath.Join(start, path))
+func (r *RequireModule) loadNodeModule(path, start string, trace *resolveTrace) (*js.Object, error) {
+	pkgName, subpath := splitPackageSpecifier(path)
+	pkgDir := filepath.Join(start, pkgName)
+	if module, err := r.loadPackageExports(pkgDir, subpath, trace); err == nil {
+		return module, nil
+	}
+
+	return r.loadAsFileOrDirectory(filepath.Join(start, path), trace)
+}
+
+// splitPackageSpecifier splits a bare module specifier into its package
+// name and the "exports" subpath it refers to ("." for the package root
+// itself), taking scoped packages ("@scope/name[/sub]") into account.
+func splitPackageSpecifier(path string) (pkgName, subpath string) {
+	parts := strings.SplitN(path, "/", 2)
+	if strings.HasPrefix(path, "@") && len(parts) == 2 {
+		scopeAndName := parts
+		rest := strings.SplitN(scopeAndName[1], "/", 2)
+		if len(rest) == 2 {
+			return scopeAndName[0] + "/" + rest[0], "./" + rest[1]
+		}
+		return path, "."
+	}
+	if len(parts) == 2 {
+		return parts[0], "./" + parts[1]
+	}
+	return path, "."
 }
 
-func (r *RequireModule) loadNodeModules(path, start string) (module *js.Object, err error) {
+func (r *RequireModule) loadNodeModules(path, start string, trace *resolveTrace) (module *js.Object, err error) {
 	for _, dir := range r.r.globalFolders {
-		if module, err = r.loadNodeModule(path, dir); err == nil {
+		if module, err = r.loadNodeModule(path, dir, trace); err == nil {
 			return
 		}
 	}
@@ -146,7 +248,7 @@ func (r *RequireModule) loadNodeModules(path, start string) (module *js.Object,
 		} else {
 			p = start
 		}
-		if module, err = r.loadNodeModule(path, p); err == nil {
+		if module, err = r.loadNodeModule(path, p, trace); err == nil {
 			return
 		}
 		if start == ".." { // Dir('..') is '.'
@@ -162,13 +264,31 @@ func (r *RequireModule) loadNodeModules(path, start string) (module *js.Object,
 	return nil, InvalidModuleError
 }
 
-func (r *RequireModule) getCurrentModulePath() string {
+// recordAlias notes that alias (a cache key computed before extension
+// resolution, e.g. "foo" for a specifier of "./foo") and real (the actual
+// resolved path, e.g. "foo.js") ended up referring to the same module, so
+// Invalidate(real) can evict alias too. A no-op if real is empty (no
+// candidate ever matched, which can't happen on the success path that
+// calls this, but keeps it safe) or equal to alias (nothing to track).
+func (r *RequireModule) recordAlias(aliases map[string][]string, real, alias string) {
+	if real == "" || real == alias {
+		return
+	}
+	aliases[real] = append(aliases[real], alias)
+}
+
+// getCurrentModuleFile returns the full path of the module whose code is
+// calling require() (the second captured frame: frame 0 is the native
+// "require" builtin itself, frame 1 its JS caller), or "" if there's no
+// such frame (e.g.
+// require() called via the Go-side Require() helper at the top level).
+func (r *RequireModule) getCurrentModuleFile() string {
 	var buf [2]js.StackFrame
 	frames := r.runtime.CaptureCallStack(2, buf[:0])
 	if len(frames) < 2 {
-		return "."
+		return ""
 	}
-	return filepath.Dir(frames[1].SrcName())
+	return frames[1].SrcName()
 }
 
 func (r *RequireModule) createModuleObject() *js.Object {
@@ -177,24 +297,52 @@ func (r *RequireModule) createModuleObject() *js.Object {
 	return module
 }
 
-func (r *RequireModule) loadModule(path string) (*js.Object, error) {
+func (r *RequireModule) loadModule(path string, trace *resolveTrace) (*js.Object, error) {
 	module := r.modules[path]
 	if module == nil {
 		module = r.createModuleObject()
 		r.modules[path] = module
 		err := r.loadModuleFile(path, module)
+		trace.record(path, err)
 		if err != nil {
 			module = nil
 			delete(r.modules, path)
+		} else {
+			r.watchPath(path)
 		}
 		return module, err
 	}
 	return module, nil
 }
 
+// getSource serves path from r.bundle's recorded files when one is in use
+// and has it, so directory/"exports" resolution (loadAsDirectory,
+// loadPackageExports) reading a package.json never touches the original
+// SourceLoader for anything Compile already walked -- including the entry
+// module's own directory, which has no caller stack frame to key a Graph
+// lookup by. Anything the bundle doesn't have (e.g. a path outside the
+// static walk) falls back to the live Registry.getSource as before.
+func (r *RequireModule) getSource(path string) ([]byte, error) {
+	if r.bundle != nil {
+		if buf, ok := r.bundle.getSource(path); ok {
+			return buf, nil
+		}
+	}
+	return r.r.getSource(path)
+}
+
 func (r *RequireModule) loadModuleFile(path string, jsModule *js.Object) error {
+	var prg *js.Program
+	var err error
 
-	prg, err := r.r.getCompiledSource(path)
+	if r.bundle != nil {
+		if p, ok, bErr := r.bundle.program(path); ok {
+			prg, err = p, bErr
+		}
+	}
+	if prg == nil && err == nil {
+		prg, err = r.r.getCompiledSource(path)
+	}
 
 	if err != nil {
 		return err
@@ -0,0 +1,48 @@
+package require
+
+import (
+	"errors"
+	"testing"
+
+	js "github.com/dop251/goja"
+)
+
+// TestNotifyChangedInvalidatesResolveCache guards against resolve()'s
+// alias-level cache (keyed by the pre-extension-resolution specifier,
+// e.g. "foo") going stale after NotifyChanged/Invalidate evicts the real,
+// extension-resolved path (e.g. "foo.js") that loadModule caches under.
+func TestNotifyChangedInvalidatesResolveCache(t *testing.T) {
+	sources := map[string]string{
+		"foo.js": "module.exports.value = 1;",
+	}
+	loader := SourceLoader(func(path string) ([]byte, error) {
+		if s, ok := sources[path]; ok {
+			return []byte(s), nil
+		}
+		return nil, errors.New("not found: " + path)
+	})
+
+	runtime := js.New()
+	rm := NewRegistry(WithLoader(loader)).Enable(runtime)
+
+	first, err := rm.resolve("./foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := first.Get("exports").ToObject(runtime).Get("value").ToInteger(); v != 1 {
+		t.Fatalf("expected initial value 1, got %d", v)
+	}
+
+	sources["foo.js"] = "module.exports.value = 2;"
+	if err := rm.NotifyChanged("foo.js"); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := rm.resolve("./foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := second.Get("exports").ToObject(runtime).Get("value").ToInteger(); v != 2 {
+		t.Fatalf("stale resolve() cache: expected reloaded value 2, got %d", v)
+	}
+}
@@ -0,0 +1,102 @@
+package require
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResolveEvent describes a single candidate path examined while resolving
+// a require() specifier. It is delivered live to any tracer registered
+// with WithResolveTracer, regardless of whether resolution as a whole
+// eventually succeeds.
+type ResolveEvent struct {
+	Specifier string // the original string passed to require()
+	Candidate string // the path that was just attempted
+	Err       error  // nil if Candidate was the match
+}
+
+// WithResolveTracer registers a callback invoked for every candidate path
+// considered during module resolution (every extension tried, every
+// node_modules ancestor walked, every package.json main considered). It is
+// intended for --debug-style logging of require() activity; it is called
+// synchronously from the resolving goroutine.
+func WithResolveTracer(tracer func(ResolveEvent)) Option {
+	return func(r *Registry) {
+		r.resolveTracer = tracer
+	}
+}
+
+// ResolveCandidate is one entry in a ResolveError's candidate list: a path
+// that was tried and the error that ruled it out.
+type ResolveCandidate struct {
+	Path string
+	Err  error
+}
+
+// ResolveError is returned by require() when a specifier cannot be
+// resolved. It records every candidate that was tried, so Error() can
+// print a multi-line report similar to Node's MODULE_NOT_FOUND, instead of
+// the bare "invalid module" that InvalidModuleError alone conveys.
+type ResolveError struct {
+	Specifier  string
+	Start      string
+	Candidates []ResolveCandidate
+}
+
+func (e *ResolveError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Cannot find module '%s'\n", e.Specifier)
+	fmt.Fprintf(&b, "Require stack:\n- %s\n", e.Start)
+	if len(e.Candidates) > 0 {
+		b.WriteString("Candidates tried:\n")
+		for _, c := range e.Candidates {
+			fmt.Fprintf(&b, "- %s: %v\n", c.Path, c.Err)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Unwrap exposes the last candidate's underlying error, so callers using
+// errors.Is/As against e.g. a filesystem permission error still see it.
+func (e *ResolveError) Unwrap() error {
+	if n := len(e.Candidates); n > 0 {
+		return e.Candidates[n-1].Err
+	}
+	return nil
+}
+
+// resolveTrace accumulates the candidates examined by a single resolve()
+// call, so that a final failure can be reported as one ResolveError and,
+// along the way, each attempt can be streamed to an optional tracer.
+type resolveTrace struct {
+	specifier string
+	start     string
+	tracer    func(ResolveEvent)
+
+	candidates []ResolveCandidate
+	// resolved is the last candidate recorded without an error, i.e. the
+	// real, extension-resolved path the specifier ultimately matched. It
+	// lets resolve() track which alias (pre-extension-resolution) cache
+	// entries correspond to which real path, so Invalidate can sweep both.
+	resolved string
+}
+
+func (t *resolveTrace) record(path string, err error) {
+	if t == nil {
+		return
+	}
+	t.candidates = append(t.candidates, ResolveCandidate{Path: path, Err: err})
+	if err == nil {
+		t.resolved = path
+	}
+	if t.tracer != nil {
+		t.tracer(ResolveEvent{Specifier: t.specifier, Candidate: path, Err: err})
+	}
+}
+
+func (t *resolveTrace) asError() error {
+	if t == nil {
+		return InvalidModuleError
+	}
+	return &ResolveError{Specifier: t.specifier, Start: t.start, Candidates: t.candidates}
+}
@@ -0,0 +1,63 @@
+package require
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	js "github.com/dop251/goja"
+)
+
+// TestResolveErrorReportsCandidatesAndTracer guards the diagnostics chunk0-5
+// added: a failed require() must return a *ResolveError listing every
+// candidate path tried, and WithResolveTracer must observe the same
+// candidates live as they're attempted.
+func TestResolveErrorReportsCandidatesAndTracer(t *testing.T) {
+	loader := SourceLoader(func(path string) ([]byte, error) {
+		return nil, errors.New("not found: " + path)
+	})
+
+	var events []ResolveEvent
+	runtime := js.New()
+	rm := NewRegistry(
+		WithLoader(loader),
+		WithResolveTracer(func(e ResolveEvent) { events = append(events, e) }),
+	).Enable(runtime)
+
+	_, err := rm.resolve("./missing")
+	if err == nil {
+		t.Fatal("expected an error for an unresolvable specifier")
+	}
+
+	resolveErr, ok := err.(*ResolveError)
+	if !ok {
+		t.Fatalf("expected *ResolveError, got %T: %v", err, err)
+	}
+	if resolveErr.Specifier != "./missing" {
+		t.Fatalf("expected Specifier %q, got %q", "./missing", resolveErr.Specifier)
+	}
+	if len(resolveErr.Candidates) == 0 {
+		t.Fatal("expected at least one candidate to be recorded")
+	}
+	for _, want := range []string{"missing", "missing.js", "missing.ts", "missing.tsx", "missing.json"} {
+		found := false
+		for _, c := range resolveErr.Candidates {
+			if strings.HasSuffix(c.Path, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("expected a candidate ending in %q, got %+v", want, resolveErr.Candidates)
+		}
+	}
+
+	if len(events) != len(resolveErr.Candidates) {
+		t.Fatalf("expected tracer to observe every candidate: got %d events for %d candidates", len(events), len(resolveErr.Candidates))
+	}
+
+	msg := resolveErr.Error()
+	if !strings.Contains(msg, "Cannot find module './missing'") {
+		t.Fatalf("expected Node-style MODULE_NOT_FOUND message, got: %s", msg)
+	}
+}
@@ -0,0 +1,191 @@
+package require
+
+import (
+	"regexp"
+	"strings"
+)
+
+// TranspileTypeScript is a built-in SourceTransformer that lets plain
+// TypeScript / ES-module sources be required directly, without a separate
+// build step. It is line-oriented and deliberately conservative: it
+// handles the common shapes produced by hand-written modules and plain
+// `tsc --target esnext` output, not the full TypeScript grammar (no
+// decorators, no multi-line generics spanning several statements). Pass
+// it to WithSourceTransformer to enable it for a Registry.
+func TranspileTypeScript(path string, src []byte) ([]byte, error) {
+	lines := strings.Split(string(src), "\n")
+	skip := commentOrTemplateMask(lines)
+
+	isTS := strings.HasSuffix(path, ".ts") || strings.HasSuffix(path, ".tsx")
+
+	var exported []string
+	out := make([]string, 0, len(lines))
+	for i, line := range lines {
+		if skip[i] {
+			out = append(out, line)
+			continue
+		}
+
+		if isTS {
+			line = stripTypeAnnotations(line)
+		}
+
+		line, name := rewriteModuleSyntax(line)
+		if name != "" {
+			exported = append(exported, name)
+		}
+
+		out = append(out, line)
+	}
+
+	for _, name := range exported {
+		out = append(out, "module.exports."+name+" = "+name+";")
+	}
+
+	return []byte(strings.Join(out, "\n")), nil
+}
+
+// commentOrTemplateMask returns, for each line, whether its *start* falls
+// inside a block comment or a template literal opened on an earlier line.
+// Lines flagged this way are passed through untouched by both the TS and
+// the ESM rewrite passes, since a naive regex could otherwise mangle text
+// that merely looks like code inside a string/comment span.
+func commentOrTemplateMask(lines []string) []bool {
+	mask := make([]bool, len(lines))
+	inBlockComment := false
+	inTemplate := false
+
+	for i, line := range lines {
+		mask[i] = inBlockComment || inTemplate
+
+		runes := []rune(line)
+		for j := 0; j < len(runes); j++ {
+			c := runes[j]
+			switch {
+			case inBlockComment:
+				if c == '*' && j+1 < len(runes) && runes[j+1] == '/' {
+					inBlockComment = false
+					j++
+				}
+			case inTemplate:
+				if c == '\\' {
+					j++
+				} else if c == '`' {
+					inTemplate = false
+				}
+			case c == '/' && j+1 < len(runes) && runes[j+1] == '/':
+				j = len(runes)
+			case c == '/' && j+1 < len(runes) && runes[j+1] == '*':
+				inBlockComment = true
+				j++
+			case c == '`':
+				inTemplate = true
+			case c == '"' || c == '\'':
+				quote := c
+				j++
+				for j < len(runes) && runes[j] != quote {
+					if runes[j] == '\\' {
+						j++
+					}
+					j++
+				}
+			}
+		}
+	}
+
+	return mask
+}
+
+var (
+	reImportTypeOnly  = regexp.MustCompile(`^(\s*)import\s+type\s+.+\s+from\s+["'][^"']+["']\s*;?\s*$`)
+	reExportTypeOnly  = regexp.MustCompile(`^(\s*)export\s+type\s+\{[^}]*\}\s*(from\s+["'][^"']+["'])?\s*;?\s*$`)
+	reImportDefault   = regexp.MustCompile(`^(\s*)import\s+([A-Za-z_$][\w$]*)\s+from\s+(["'][^"']+["'])\s*;?\s*$`)
+	reImportNamed     = regexp.MustCompile(`^(\s*)import\s+\{([^}]+)\}\s+from\s+(["'][^"']+["'])\s*;?\s*$`)
+	reImportNamespace = regexp.MustCompile(`^(\s*)import\s+\*\s+as\s+([A-Za-z_$][\w$]*)\s+from\s+(["'][^"']+["'])\s*;?\s*$`)
+	reImportBare      = regexp.MustCompile(`^(\s*)import\s+(["'][^"']+["'])\s*;?\s*$`)
+	reExportDefault   = regexp.MustCompile(`^(\s*)export\s+default\s+(.+)$`)
+	reExportNamed     = regexp.MustCompile(`^(\s*)export\s+\{([^}]+)\}\s*;?\s*$`)
+	reExportDecl      = regexp.MustCompile(`^(\s*)export\s+(const|let|var|function\*?|class)\s+([A-Za-z_$][\w$]*)`)
+)
+
+// rewriteModuleSyntax rewrites a single top-level ESM statement into its
+// CommonJS equivalent. It returns the (possibly unchanged) line and, when
+// the line declares `export function/class/const foo`, the name that
+// needs a trailing `module.exports.foo = foo;` once the declaration has
+// run. Dynamic `import(...)` expressions never match these patterns (they
+// aren't anchored at the start of a statement) and are left alone.
+// `import type {...} from "m"` and `export type {...}` carry no runtime
+// value -- like a `type X = ...;` alias, they're elided entirely rather
+// than rewritten into a require() that would either fail (the module may
+// export no runtime members at all) or pull in a dependency never needed
+// at runtime.
+func rewriteModuleSyntax(line string) (string, string) {
+	if reImportTypeOnly.MatchString(line) || reExportTypeOnly.MatchString(line) {
+		return "", ""
+	}
+	if m := reImportDefault.FindStringSubmatch(line); m != nil {
+		return m[1] + "const " + m[2] + " = require(" + m[3] + ");", ""
+	}
+	if m := reImportNamespace.FindStringSubmatch(line); m != nil {
+		return m[1] + "const " + m[2] + " = require(" + m[3] + ");", ""
+	}
+	if m := reImportNamed.FindStringSubmatch(line); m != nil {
+		return m[1] + "const {" + strings.TrimSpace(m[2]) + "} = require(" + m[3] + ");", ""
+	}
+	if m := reImportBare.FindStringSubmatch(line); m != nil {
+		return m[1] + "require(" + m[2] + ");", ""
+	}
+	if m := reExportDefault.FindStringSubmatch(line); m != nil {
+		return m[1] + "module.exports.default = " + m[2], ""
+	}
+	if m := reExportNamed.FindStringSubmatch(line); m != nil {
+		var assigns []string
+		for _, part := range strings.Split(m[2], ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			if as := strings.SplitN(part, " as ", 2); len(as) == 2 {
+				assigns = append(assigns, "module.exports."+strings.TrimSpace(as[1])+" = "+strings.TrimSpace(as[0])+";")
+			} else {
+				assigns = append(assigns, "module.exports."+part+" = "+part+";")
+			}
+		}
+		return m[1] + strings.Join(assigns, " "), ""
+	}
+	if m := reExportDecl.FindStringSubmatch(line); m != nil {
+		return m[1] + m[2] + " " + m[3] + line[len(m[0]):], m[3]
+	}
+
+	return line, ""
+}
+
+var (
+	reParamType   = regexp.MustCompile(`([(,]\s*[A-Za-z_$][\w$]*\??)\s*:\s*[^,)=]+`)
+	reVarType     = regexp.MustCompile(`((?:let|const|var)\s+[A-Za-z_$][\w$]*)\s*:\s*[^=;,)]+`)
+	reReturnType  = regexp.MustCompile(`\)\s*:\s*[A-Za-z_$][\w$.<>\[\] |&]*\s*\{`)
+	reGenericDecl = regexp.MustCompile(`((?:function\*?|class)\s+[A-Za-z_$][\w$]*)<[^>(]+>`)
+	reAsCast      = regexp.MustCompile(`\s+as\s+[A-Za-z_$][\w$.<>\[\]]*`)
+	reInterface   = regexp.MustCompile(`^\s*(export\s+)?interface\s+\S+.*\{?\s*$`)
+	reTypeAlias   = regexp.MustCompile(`^\s*(export\s+)?type\s+\S+\s*=.*;?\s*$`)
+)
+
+// stripTypeAnnotations removes the subset of TypeScript syntax that has no
+// JavaScript meaning: parameter/variable/return type annotations, `as`
+// casts and bare generic type parameters. It does not attempt to parse
+// multi-line `interface` bodies; callers that need those stripped
+// entirely should precompute the line range (the common case is a
+// single-line or self-closing interface, which this does handle).
+func stripTypeAnnotations(line string) string {
+	if reInterface.MatchString(line) || reTypeAlias.MatchString(line) {
+		return ""
+	}
+
+	line = reParamType.ReplaceAllString(line, "$1")
+	line = reVarType.ReplaceAllString(line, "$1")
+	line = reReturnType.ReplaceAllString(line, ") {")
+	line = reGenericDecl.ReplaceAllString(line, "$1")
+	line = reAsCast.ReplaceAllString(line, "")
+
+	return line
+}
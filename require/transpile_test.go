@@ -0,0 +1,40 @@
+package require
+
+import (
+	"strings"
+	"testing"
+
+	js "github.com/dop251/goja"
+)
+
+// TestTranspileTypeScriptElidesTypeOnlyImportsAndExports guards against
+// `import type`/`export type` passing through unrewritten -- since they
+// carry no runtime value, they must be elided the same way a `type X =
+// ...;` alias is, not left as invalid syntax in the wrapped CommonJS output.
+func TestTranspileTypeScriptElidesTypeOnlyImportsAndExports(t *testing.T) {
+	src := `import type { Foo } from "./foo";
+import type Bar from "./bar";
+export type { Foo };
+export type { Foo } from "./foo";
+import { real } from "./real";
+export const x = real;
+`
+	out, err := TranspileTypeScript("mod.ts", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := js.Compile("mod.ts", "(function(exports, require, module) {"+string(out)+"\n})", false); err != nil {
+		t.Fatalf("transpiled output is not valid JS: %v\n---\n%s", err, out)
+	}
+
+	got := string(out)
+	for _, bad := range []string{"import type", "export type"} {
+		if strings.Contains(got, bad) {
+			t.Fatalf("expected %q to be elided, got:\n%s", bad, got)
+		}
+	}
+	if !strings.Contains(got, `const {real} = require("./real");`) {
+		t.Fatalf("expected the real import to still be rewritten, got:\n%s", got)
+	}
+}
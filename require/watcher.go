@@ -0,0 +1,88 @@
+package require
+
+import js "github.com/dop251/goja"
+
+// Watcher is the subset of fsnotify.Watcher (or any equivalent file
+// watcher) that RequireModule needs in order to support hot reloading.
+// Callers that already depend on fsnotify can satisfy this with
+// *fsnotify.Watcher directly, since its Add/Remove/Close signatures match.
+type Watcher interface {
+	Add(path string) error
+	Remove(path string) error
+	Close() error
+}
+
+// WithWatcher enables hot module reloading: every module path resolved by
+// loadModule is registered with w, and RequireModule.NotifyChanged should
+// be called by the host (typically from its own fsnotify event loop, since
+// a goja.Runtime is not safe for concurrent use) whenever w reports that
+// one of those paths changed.
+func WithWatcher(w Watcher) Option {
+	return func(r *Registry) {
+		r.watcher = w
+	}
+}
+
+// OnModuleReloaded registers a callback that is invoked after a watched
+// module has been transparently reloaded, with its freshly evaluated
+// exports, so host Go code can re-bind any functions it pulled out of the
+// old ones.
+func (r *RequireModule) OnModuleReloaded(cb func(path string, newExports js.Value)) {
+	r.onModuleReload = cb
+}
+
+// Invalidate evicts path from the module cache, so the next require() of
+// it (directly or transitively) re-reads and re-evaluates its source.
+// It is safe to call for a path that was never loaded.
+func (r *RequireModule) Invalidate(path string) {
+	path = filepathClean(path)
+	delete(r.modules, path)
+	delete(r.nodeModules, path)
+
+	for _, alias := range r.moduleAliases[path] {
+		delete(r.modules, alias)
+	}
+	delete(r.moduleAliases, path)
+
+	for _, alias := range r.nodeModuleAliases[path] {
+		delete(r.nodeModules, alias)
+	}
+	delete(r.nodeModuleAliases, path)
+
+	if r.r != nil {
+		r.r.Lock()
+		delete(r.r.compiled, path)
+		r.r.Unlock()
+	}
+}
+
+// NotifyChanged should be called whenever the host's file watcher reports
+// that path has changed on disk. It invalidates the cached module,
+// re-runs it immediately and reports the new
+// exports via the OnModuleReloaded callback. Must be called from the same
+// goroutine that owns the runtime.
+func (r *RequireModule) NotifyChanged(path string) error {
+	path = filepathClean(path)
+	r.Invalidate(path)
+
+	module, err := r.loadModule(path, nil)
+	if err != nil {
+		return err
+	}
+
+	if r.onModuleReload != nil {
+		r.onModuleReload(path, module.Get("exports"))
+	}
+
+	return nil
+}
+
+// watchPath registers path with the configured watcher, if any. Errors are
+// ignored: a module that can't be watched (e.g. it lives inside a ZIP
+// archive rather than on disk) simply never reloads, which is preferable
+// to failing the require() that loaded it.
+func (r *RequireModule) watchPath(path string) {
+	if r.watcher != nil {
+		_ = r.watcher.Add(path)
+	}
+}
@@ -0,0 +1,92 @@
+package require
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// NewZipSourceLoader reads the ZIP archive exposed by r (of the given
+// size) into memory and returns a SourceLoader that serves module sources
+// straight out of it. Every entry is read once, up front, and kept in a
+// map[string][]byte keyed by its archive-internal path (e.g.
+// "node_modules/foo/index.js"), so resolve.go's loadAsFileOrDirectory,
+// loadIndex and loadNodeModules can walk the archive exactly as they would
+// a directory on disk.
+func NewZipSourceLoader(r io.ReaderAt, size int64) (SourceLoader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("require: not a valid zip archive: %w", err)
+	}
+
+	entries := make(map[string][]byte, len(zr.File))
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("require: reading %s from zip: %w", f.Name, err)
+		}
+		buf, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("require: reading %s from zip: %w", f.Name, err)
+		}
+		entries[zipEntryKey(f.Name)] = buf
+	}
+
+	return func(path string) ([]byte, error) {
+		if buf, ok := entries[zipEntryKey(path)]; ok {
+			return buf, nil
+		}
+		return nil, fmt.Errorf("require: %s not found in zip archive", path)
+	}, nil
+}
+
+// zipEntryKey normalises both archive entry names (which always use "/"
+// and never have a leading slash) and filesystem-style paths produced by
+// filepath.Join in resolve.go (which on some platforms use the OS
+// separator) so the two can be compared directly.
+func zipEntryKey(p string) string {
+	p = filepath.ToSlash(p)
+	return strings.TrimPrefix(p, "/")
+}
+
+// WithZipArchive mounts the ZIP archive exposed by r under mountPoint
+// (e.g. "/plugin") as a virtual root. Requests for paths under mountPoint
+// are served from the archive; everything else falls through to the
+// Registry's configured SourceLoader (or the filesystem, if none was set),
+// so a single Registry can mix a bundled plugin with on-disk modules.
+// Unlike wrapping srcLoader directly, the mount point and archive loader
+// are kept as their own Registry fields and consulted by getSource at
+// resolve time, so WithZipArchive and WithLoader can be passed to
+// NewRegistry in either order without one silently clobbering the other.
+func WithZipArchive(mountPoint string, r io.ReaderAt, size int64) (Option, error) {
+	zipLoader, err := NewZipSourceLoader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	mountPoint = filepath.Clean(mountPoint)
+
+	return func(reg *Registry) {
+		reg.zipMountPoint = mountPoint
+		reg.zipLoader = zipLoader
+	}, nil
+}
+
+func underMountPoint(path, mountPoint string) (string, bool) {
+	path = filepath.Clean(path)
+	if path == mountPoint {
+		return "", true
+	}
+	prefix := mountPoint + string(filepath.Separator)
+	if strings.HasPrefix(path, prefix) {
+		return strings.TrimPrefix(path, prefix), true
+	}
+	return "", false
+}
@@ -0,0 +1,70 @@
+package require
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func buildTestZip(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// TestWithZipArchiveOrderIndependent guards against WithZipArchive and
+// WithLoader clobbering each other depending on the order they're passed to
+// NewRegistry in -- both orders must resolve a mounted archive entry and
+// fall back to the plain loader for anything outside the mount point.
+func TestWithZipArchiveOrderIndependent(t *testing.T) {
+	zr := buildTestZip(t, map[string]string{"index.js": "module.exports.value = 1;"})
+
+	plain := SourceLoader(func(path string) ([]byte, error) {
+		if path == "/host/other.js" {
+			return []byte("module.exports.value = 2;"), nil
+		}
+		return nil, errors.New("not found: " + path)
+	})
+
+	zipOpt, err := WithZipArchive("/plugin", zr, zr.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range [][]Option{
+		{zipOpt, WithLoader(plain)},
+		{WithLoader(plain), zipOpt},
+	} {
+		reg := NewRegistry(opts...)
+
+		buf, err := reg.getSource("/plugin/index.js")
+		if err != nil {
+			t.Fatalf("expected mounted archive entry to resolve, got: %v", err)
+		}
+		if string(buf) != "module.exports.value = 1;" {
+			t.Fatalf("unexpected archive content: %s", buf)
+		}
+
+		buf, err = reg.getSource("/host/other.js")
+		if err != nil {
+			t.Fatalf("expected fallback loader to resolve, got: %v", err)
+		}
+		if string(buf) != "module.exports.value = 2;" {
+			t.Fatalf("unexpected fallback content: %s", buf)
+		}
+	}
+}